@@ -0,0 +1,228 @@
+// Copyright 2011 John E. Barham. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgsqldriver
+
+/*
+#include <stdlib.h>
+#include <libpq-fe.h>
+*/
+import "C"
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// quoteIdent double-quotes a PostgreSQL identifier, escaping embedded
+// double quotes per the standard identifier-quoting rule so a channel
+// name can't break out of the quoted LISTEN/UNLISTEN statement.
+func quoteIdent(ident string) string {
+	return `"` + strings.Replace(ident, `"`, `""`, -1) + `"`
+}
+
+// Notification is a single asynchronous NOTIFY delivered by the server to
+// a channel the Listener is subscribed to.
+type Notification struct {
+	Channel string
+	PID     int
+	Payload string
+}
+
+// Backoff computes how long a Listener should wait before its next
+// reconnect attempt, given the number of consecutive failures so far
+// (attempt starts at 1).
+type Backoff func(attempt int) time.Duration
+
+// Listener subscribes to PostgreSQL LISTEN/NOTIFY channels and delivers
+// Notifications on a Go channel. It owns a dedicated connection, separate
+// from any *sql.DB pool, and reconnects automatically using the supplied
+// Backoff if that connection drops.
+type Listener struct {
+	dsn     string
+	backoff Backoff
+
+	notify chan Notification
+	quit   chan bool
+
+	mu       sync.Mutex
+	channels map[string]bool
+	conn     *driverConn
+}
+
+// NewListener dials dsn and starts the Listener's background delivery
+// goroutine. backoff controls the delay between reconnect attempts after
+// the underlying connection is lost; a nil backoff is not allowed.
+func NewListener(dsn string, backoff Backoff) (*Listener, error) {
+	c, err := (&postgresDriver{}).Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	l := &Listener{
+		dsn:      dsn,
+		backoff:  backoff,
+		notify:   make(chan Notification, 32),
+		quit:     make(chan bool),
+		channels: make(map[string]bool),
+		conn:     c.(*driverConn),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Notify returns the channel Notifications are delivered on.
+func (l *Listener) Notify() <-chan Notification {
+	return l.notify
+}
+
+// Listen subscribes to channel. Subscriptions are re-issued automatically
+// after a reconnect.
+func (l *Listener) Listen(channel string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.conn.Exec("LISTEN "+quoteIdent(channel), nil); err != nil {
+		return err
+	}
+	l.channels[channel] = true
+	return nil
+}
+
+// Unlisten cancels a subscription established with Listen.
+func (l *Listener) Unlisten(channel string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.conn.Exec("UNLISTEN "+quoteIdent(channel), nil); err != nil {
+		return err
+	}
+	l.channels[channel] = false
+	return nil
+}
+
+// UnlistenAll cancels every subscription on this Listener.
+func (l *Listener) UnlistenAll() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.conn.Exec("UNLISTEN *", nil); err != nil {
+		return err
+	}
+	l.channels = make(map[string]bool)
+	return nil
+}
+
+// Ping runs a trivial query to detect a dead socket before the caller
+// relies on the Listener's connection for something more important.
+func (l *Listener) Ping() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.conn.Exec("SELECT 1", nil)
+	return err
+}
+
+// Close stops the background goroutine and closes the underlying
+// connection. Close does not close the Notify channel.
+func (l *Listener) Close() error {
+	close(l.quit)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conn.Close()
+}
+
+// run polls for notifications until Close is called, reconnecting with
+// the configured Backoff whenever the connection is lost.
+func (l *Listener) run() {
+	attempt := 0
+	for {
+		if stopped := l.poll(); stopped {
+			return
+		}
+		attempt++
+		select {
+		case <-l.quit:
+			return
+		case <-time.After(l.backoff(attempt)):
+		}
+		if err := l.reconnect(); err == nil {
+			attempt = 0
+		}
+	}
+}
+
+// poll repeatedly calls PQconsumeInput/PQnotifies, delivering any pending
+// Notifications, until the connection fails or Close is requested. It
+// returns true if the caller asked to stop, false if the connection died
+// and run should reconnect.
+func (l *Listener) poll() (stopped bool) {
+	for {
+		select {
+		case <-l.quit:
+			return true
+		default:
+		}
+		notifications, ok := l.consumeOnce()
+		if !ok {
+			return false
+		}
+		for _, n := range notifications {
+			select {
+			case l.notify <- n:
+			case <-l.quit:
+				return true
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// consumeOnce drains whatever notifications libpq currently has buffered
+// for l.conn, holding l.mu only long enough to touch the connection so a
+// slow Notify() consumer downstream can't block Listen/Unlisten/Ping. It
+// returns false if the connection has failed.
+func (l *Listener) consumeOnce() (notifications []Notification, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if C.PQconsumeInput(l.conn.db) == 0 {
+		return nil, false
+	}
+	for {
+		n := C.PQnotifies(l.conn.db)
+		if n == nil {
+			break
+		}
+		notifications = append(notifications, Notification{
+			Channel: C.GoString(n.relname),
+			PID:     int(n.be_pid),
+			Payload: C.GoString(n.extra),
+		})
+		C.PQfreemem(unsafe.Pointer(n))
+	}
+	return notifications, true
+}
+
+// reconnect replaces l.conn with a fresh connection and re-issues LISTEN
+// for every channel that was active before the old connection dropped.
+func (l *Listener) reconnect() error {
+	c, err := (&postgresDriver{}).Open(l.dsn)
+	if err != nil {
+		return err
+	}
+	conn := c.(*driverConn)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for channel, on := range l.channels {
+		if !on {
+			continue
+		}
+		if _, err := conn.Exec("LISTEN "+quoteIdent(channel), nil); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	l.conn.Close()
+	l.conn = conn
+	return nil
+}
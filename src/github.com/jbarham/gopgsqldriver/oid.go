@@ -0,0 +1,31 @@
+// Copyright 2011 John E. Barham. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pgsqldriver
+
+// PostgreSQL built-in type OIDs, from src/include/catalog/pg_type.h.
+// Only the subset of types driverRows.Next knows how to decode are listed.
+const (
+	BOOLOID        = 16
+	BYTEAOID       = 17
+	CHAROID        = 18
+	NAMEOID        = 19
+	INT8OID        = 20
+	INT2OID        = 21
+	INT4OID        = 23
+	TEXTOID        = 25
+	OIDOID         = 26
+	XIDOID         = 28
+	FLOAT4OID      = 700
+	FLOAT8OID      = 701
+	BPCHAROID      = 1042
+	VARCHAROID     = 1043
+	DATEOID        = 1082
+	TIMEOID        = 1083
+	TIMESTAMPOID   = 1114
+	TIMESTAMPTZOID = 1184
+	INTERVALOID    = 1186
+	TIMETZOID      = 1266
+	NUMERICOID     = 1700
+)
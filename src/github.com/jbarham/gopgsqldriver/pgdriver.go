@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// pgsqldriver is a PostgreSQL driver for the experimental Go SQL database package.
+// pgsqldriver is a PostgreSQL driver for the database/sql package.
 package pgsqldriver
 
 /*
@@ -27,28 +27,33 @@ static void freeCharArray(char **a, int size) {
 import "C"
 
 import (
-	"os"
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"time"
+	"io"
+	"math"
 	"runtime"
-	"unsafe"
-	"strings"
 	"strconv"
-	"encoding/hex"
-	"exp/sql"
-	"exp/sql/driver"
+	"strings"
+	"time"
+	"unsafe"
 )
 
-func connError(db *C.PGconn) os.Error {
-	return os.NewError("conn error:" + C.GoString(C.PQerrorMessage(db)))
+func connError(db *C.PGconn) error {
+	return errors.New("conn error:" + C.GoString(C.PQerrorMessage(db)))
 }
 
-func resultError(res *C.PGresult) os.Error {
+func resultError(res *C.PGresult) error {
 	serr := C.GoString(C.PQresultErrorMessage(res))
 	if serr == "" {
 		return nil
 	}
-	return os.NewError("result error: " + serr)
+	return errors.New("result error: " + serr)
 }
 
 const timeFormat = "2006-01-02 15:04:05.000000-07"
@@ -59,7 +64,7 @@ type postgresDriver struct{}
 // Each parameter setting is in the form 'keyword=value'.
 // See http://www.postgresql.org/docs/9.0/static/libpq-connect.html#LIBPQ-PQCONNECTDBPARAMS
 // for a list of recognized parameters.
-func (d *postgresDriver) Open(name string) (conn driver.Conn, err os.Error) {
+func (d *postgresDriver) Open(name string) (conn driver.Conn, err error) {
 	cparams := C.CString(name)
 	defer C.free(unsafe.Pointer(cparams))
 	db := C.PQconnectdb(cparams)
@@ -78,10 +83,14 @@ type driverConn struct {
 	stmtNum int
 }
 
-// Check that driverConn implements driver.Execer interface.
-var _ driver.Execer = (*driverConn)(nil)
+// Check that driverConn implements the driver interfaces it's meant to.
+var (
+	_ driver.Execer         = (*driverConn)(nil)
+	_ driver.ExecerContext  = (*driverConn)(nil)
+	_ driver.QueryerContext = (*driverConn)(nil)
+)
 
-func (c *driverConn) exec(stmt string, args []interface{}) (cres *C.PGresult) {
+func (c *driverConn) exec(stmt string, args []driver.Value) (cres *C.PGresult) {
 	stmtstr := C.CString(stmt)
 	defer C.free(unsafe.Pointer(stmtstr))
 	if len(args) == 0 {
@@ -89,30 +98,171 @@ func (c *driverConn) exec(stmt string, args []interface{}) (cres *C.PGresult) {
 	} else {
 		cargs := buildCArgs(args)
 		defer C.freeCharArray(cargs, C.int(len(args)))
-		cres = C.PQexecParams(c.db, stmtstr, C.int(len(args)), nil, cargs, nil, nil, 0)
+		cres = C.PQexecParams(c.db, stmtstr, C.int(len(args)), nil, cargs, nil, nil, 1)
 	}
 	return cres
 }
 
-func (c *driverConn) Exec(query string, args []interface{}) (res driver.Result, err os.Error) {
+func (c *driverConn) Exec(query string, args []driver.Value) (driver.Result, error) {
 	cres := c.exec(query, args)
-	if err = resultError(cres); err != nil {
-		C.PQclear(cres)
-		return
-	}
 	defer C.PQclear(cres)
+	if err := resultError(cres); err != nil {
+		return nil, err
+	}
+	return commandTagResult(cres)
+}
+
+func commandTagResult(cres *C.PGresult) (driver.Result, error) {
 	ns := C.GoString(C.PQcmdTuples(cres))
 	if ns == "" {
-		return driver.DDLSuccess, nil
+		return driver.RowsAffected(0), nil
 	}
-	rowsAffected, err := strconv.Atoi64(ns)
+	rowsAffected, err := strconv.ParseInt(ns, 10, 64)
 	if err != nil {
-		return
+		return nil, err
 	}
 	return driver.RowsAffected(rowsAffected), nil
 }
 
-func (c *driverConn) Prepare(query string) (driver.Stmt, os.Error) {
+func namedValues(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	return vals
+}
+
+// waitReady blocks, via non-blocking libpq calls, until the command
+// in flight on db finishes or ctx is done. If ctx is done first, it
+// issues a server-side PQcancel and drains the connection so it's left
+// reusable for the next query, then returns ctx.Err().
+func waitReady(ctx context.Context, db *C.PGconn) error {
+	for {
+		if C.PQisBusy(db) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			cancelQuery(db)
+			for C.PQisBusy(db) != 0 {
+				if C.PQconsumeInput(db) == 0 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			drainResults(db)
+			return ctx.Err()
+		default:
+		}
+		if C.PQconsumeInput(db) == 0 {
+			return connError(db)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// cancelQuery asks the server to abort the command currently running on
+// db, via libpq's out-of-band PQcancel mechanism.
+func cancelQuery(db *C.PGconn) {
+	c := C.PQgetCancel(db)
+	if c == nil {
+		return
+	}
+	defer C.PQfreeCancel(c)
+	var errbuf [256]C.char
+	C.PQcancel(c, &errbuf[0], C.int(len(errbuf)))
+}
+
+// drainResults discards every pending PGresult for the in-flight command,
+// leaving the connection ready for the next query.
+func drainResults(db *C.PGconn) {
+	for {
+		res := C.PQgetResult(db)
+		if res == nil {
+			return
+		}
+		C.PQclear(res)
+	}
+}
+
+// collectResult reads every PGresult produced by the in-flight command
+// and returns the last one, matching what the blocking PQexec* calls
+// return for a single statement.
+func collectResult(db *C.PGconn) (res *C.PGresult, err error) {
+	for {
+		r := C.PQgetResult(db)
+		if r == nil {
+			return res, err
+		}
+		if res != nil {
+			C.PQclear(res)
+		}
+		res = r
+		if e := resultError(res); e != nil {
+			err = e
+		}
+	}
+}
+
+// sendQuery starts query on c.db without waiting for its result. Queries
+// that return rows always go through PQsendQueryParams so their results
+// come back in the binary format driverRows.Next decodes.
+func (c *driverConn) sendQuery(query string, args []driver.Value, wantRows bool) error {
+	stmtstr := C.CString(query)
+	defer C.free(unsafe.Pointer(stmtstr))
+	var ok C.int
+	if len(args) == 0 && !wantRows {
+		ok = C.PQsendQuery(c.db, stmtstr)
+	} else {
+		cargs := buildCArgs(args)
+		defer C.freeCharArray(cargs, C.int(len(args)))
+		ok = C.PQsendQueryParams(c.db, stmtstr, C.int(len(args)), nil, cargs, nil, nil, 1)
+	}
+	if ok == 0 {
+		return connError(c.db)
+	}
+	return nil
+}
+
+// ExecContext implements driver.ExecerContext using non-blocking libpq
+// calls, so a canceled or timed-out ctx aborts the statement server-side
+// via PQcancel instead of leaving the caller blocked on a runaway query.
+func (c *driverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.sendQuery(query, namedValues(args), false); err != nil {
+		return nil, err
+	}
+	if err := waitReady(ctx, c.db); err != nil {
+		return nil, err
+	}
+	res, err := collectResult(c.db)
+	if res != nil {
+		defer C.PQclear(res)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return commandTagResult(res)
+}
+
+// QueryContext implements driver.QueryerContext; see ExecContext.
+func (c *driverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.sendQuery(query, namedValues(args), true); err != nil {
+		return nil, err
+	}
+	if err := waitReady(ctx, c.db); err != nil {
+		return nil, err
+	}
+	res, err := collectResult(c.db)
+	if err != nil {
+		if res != nil {
+			C.PQclear(res)
+		}
+		return nil, err
+	}
+	return newResult(res), nil
+}
+
+func (c *driverConn) Prepare(query string) (driver.Stmt, error) {
 	// Generate unique statement name.
 	stmtname := strconv.Itoa(c.stmtNum)
 	cstmtname := C.CString(stmtname)
@@ -139,7 +289,7 @@ func (c *driverConn) Prepare(query string) (driver.Stmt, os.Error) {
 	return statement, nil
 }
 
-func (c *driverConn) Close() os.Error {
+func (c *driverConn) Close() error {
 	if c != nil && c.db != nil {
 		C.PQfinish(c.db)
 		c.db = nil
@@ -148,7 +298,7 @@ func (c *driverConn) Close() os.Error {
 	return nil
 }
 
-func (c *driverConn) Begin() (driver.Tx, os.Error) {
+func (c *driverConn) Begin() (driver.Tx, error) {
 	if _, err := c.Exec("BEGIN", nil); err != nil {
 		return nil, err
 	}
@@ -156,14 +306,140 @@ func (c *driverConn) Begin() (driver.Tx, os.Error) {
 	return c, nil
 }
 
-func (c *driverConn) Commit() (err os.Error) {
-	_, err = c.Exec("COMMIT", nil)
-	return
+func (c *driverConn) Commit() error {
+	_, err := c.Exec("COMMIT", nil)
+	return err
 }
 
-func (c *driverConn) Rollback() (err os.Error) {
-	_, err = c.Exec("ROLLBACK", nil)
-	return
+func (c *driverConn) Rollback() error {
+	_, err := c.Exec("ROLLBACK", nil)
+	return err
+}
+
+// cBytes copies a Go byte slice into newly C-malloc'd memory and returns a
+// pointer/length pair suitable for passing to libpq. The caller owns the
+// returned memory and must C.free it.
+func cBytes(b []byte) (*C.char, C.int) {
+	if len(b) == 0 {
+		return nil, 0
+	}
+	p := C.malloc(C.size_t(len(b)))
+	copy((*[1 << 30]byte)(p)[:len(b):len(b)], b)
+	return (*C.char)(p), C.int(len(b))
+}
+
+// CopyIn begins a "COPY table (columns) FROM STDIN" bulk load and returns
+// an io.WriteCloser that streams row data (tab-separated text format, one
+// row per line, matching the server's default COPY format) to the server.
+// It is a driver-specific extension reached via a type assertion:
+//
+//	w, err := conn.(interface {
+//		CopyIn(table string, columns []string) (io.WriteCloser, error)
+//	}).CopyIn("mytable", []string{"a", "b"})
+func (c *driverConn) CopyIn(table string, columns []string) (io.WriteCloser, error) {
+	stmt := "COPY " + quoteIdent(table)
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = quoteIdent(col)
+		}
+		stmt += " (" + strings.Join(quoted, ", ") + ")"
+	}
+	stmt += " FROM STDIN"
+	cstmt := C.CString(stmt)
+	defer C.free(unsafe.Pointer(cstmt))
+	res := C.PQexec(c.db, cstmt)
+	defer C.PQclear(res)
+	if C.PQresultStatus(res) != C.PGRES_COPY_IN {
+		return nil, resultError(res)
+	}
+	return &copyWriter{c: c}, nil
+}
+
+// CopyOut runs a "COPY ... TO STDOUT" query and returns an io.ReadCloser
+// that streams the server's row data back to the caller.
+func (c *driverConn) CopyOut(query string) (io.ReadCloser, error) {
+	cstmt := C.CString(query)
+	defer C.free(unsafe.Pointer(cstmt))
+	res := C.PQexec(c.db, cstmt)
+	defer C.PQclear(res)
+	if C.PQresultStatus(res) != C.PGRES_COPY_OUT {
+		return nil, resultError(res)
+	}
+	return &copyReader{c: c}, nil
+}
+
+type copyWriter struct {
+	c *driverConn
+}
+
+var _ io.WriteCloser = (*copyWriter)(nil)
+
+func (w *copyWriter) Write(p []byte) (int, error) {
+	cdata, clen := cBytes(p)
+	defer C.free(unsafe.Pointer(cdata))
+	if C.PQputCopyData(w.c.db, cdata, clen) != 1 {
+		return 0, connError(w.c.db)
+	}
+	return len(p), nil
+}
+
+// Close ends the COPY and waits for the server to confirm it committed
+// the load, surfacing any server-side error (e.g. a constraint violation)
+// that only becomes visible once the copy completes.
+func (w *copyWriter) Close() error {
+	if C.PQputCopyEnd(w.c.db, nil) != 1 {
+		return connError(w.c.db)
+	}
+	res := C.PQgetResult(w.c.db)
+	defer C.PQclear(res)
+	return resultError(res)
+}
+
+type copyReader struct {
+	c   *driverConn
+	buf []byte
+}
+
+var _ io.ReadCloser = (*copyReader)(nil)
+
+func (r *copyReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		var cbuf *C.char
+		n := C.PQgetCopyData(r.c.db, &cbuf, 0)
+		switch {
+		case n == -1:
+			res := C.PQgetResult(r.c.db)
+			defer C.PQclear(res)
+			if err := resultError(res); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		case n == -2:
+			return 0, connError(r.c.db)
+		}
+		r.buf = C.GoBytes(unsafe.Pointer(cbuf), n)
+		C.PQfreemem(unsafe.Pointer(cbuf))
+	}
+	nCopied := copy(p, r.buf)
+	r.buf = r.buf[nCopied:]
+	return nCopied, nil
+}
+
+// Close drains any copy data the caller didn't read, so the connection is
+// left in a reusable state rather than wedged mid-COPY.
+func (r *copyReader) Close() error {
+	for {
+		var cbuf *C.char
+		n := C.PQgetCopyData(r.c.db, &cbuf, 0)
+		if n < 0 {
+			break
+		}
+		C.PQfreemem(unsafe.Pointer(cbuf))
+	}
+	res := C.PQgetResult(r.c.db)
+	C.PQclear(res)
+	return nil
 }
 
 type driverStmt struct {
@@ -173,33 +449,33 @@ type driverStmt struct {
 	nparams int
 }
 
+var (
+	_ driver.StmtExecContext  = (*driverStmt)(nil)
+	_ driver.StmtQueryContext = (*driverStmt)(nil)
+)
+
 func (s *driverStmt) NumInput() int {
 	return s.nparams
 }
 
-func (s *driverStmt) exec(params []interface{}) *C.PGresult {
+func (s *driverStmt) exec(params []driver.Value) *C.PGresult {
 	stmtName := C.CString(s.name)
 	defer C.free(unsafe.Pointer(stmtName))
 	cparams := buildCArgs(params)
 	defer C.freeCharArray(cparams, C.int(len(params)))
-	return C.PQexecPrepared(s.db, stmtName, C.int(len(params)), cparams, nil, nil, 0)
+	return C.PQexecPrepared(s.db, stmtName, C.int(len(params)), cparams, nil, nil, 1)
 }
 
-func (s *driverStmt) Exec(args []interface{}) (res driver.Result, err os.Error) {
+func (s *driverStmt) Exec(args []driver.Value) (driver.Result, error) {
 	cres := s.exec(args)
-	if err = resultError(cres); err != nil {
-		C.PQclear(cres)
-		return
-	}
 	defer C.PQclear(cres)
-	rowsAffected, err := strconv.Atoi64(C.GoString(C.PQcmdTuples(cres)))
-	if err != nil {
-		return
+	if err := resultError(cres); err != nil {
+		return nil, err
 	}
-	return driver.RowsAffected(rowsAffected), nil
+	return commandTagResult(cres)
 }
 
-func (s *driverStmt) Query(args []interface{}) (driver.Rows, os.Error) {
+func (s *driverStmt) Query(args []driver.Value) (driver.Rows, error) {
 	cres := s.exec(args)
 	if err := resultError(cres); err != nil {
 		C.PQclear(cres)
@@ -208,7 +484,54 @@ func (s *driverStmt) Query(args []interface{}) (driver.Rows, os.Error) {
 	return newResult(cres), nil
 }
 
-func (s *driverStmt) Close() os.Error {
+func (s *driverStmt) sendExec(args []driver.Value) error {
+	stmtName := C.CString(s.name)
+	defer C.free(unsafe.Pointer(stmtName))
+	cargs := buildCArgs(args)
+	defer C.freeCharArray(cargs, C.int(len(args)))
+	if C.PQsendQueryPrepared(s.db, stmtName, C.int(len(args)), cargs, nil, nil, 1) == 0 {
+		return connError(s.db)
+	}
+	return nil
+}
+
+// ExecContext implements driver.StmtExecContext; see driverConn.ExecContext.
+func (s *driverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.sendExec(namedValues(args)); err != nil {
+		return nil, err
+	}
+	if err := waitReady(ctx, s.db); err != nil {
+		return nil, err
+	}
+	res, err := collectResult(s.db)
+	if res != nil {
+		defer C.PQclear(res)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return commandTagResult(res)
+}
+
+// QueryContext implements driver.StmtQueryContext; see driverConn.ExecContext.
+func (s *driverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.sendExec(namedValues(args)); err != nil {
+		return nil, err
+	}
+	if err := waitReady(ctx, s.db); err != nil {
+		return nil, err
+	}
+	res, err := collectResult(s.db)
+	if err != nil {
+		if res != nil {
+			C.PQclear(res)
+		}
+		return nil, err
+	}
+	return newResult(res), nil
+}
+
+func (s *driverStmt) Close() error {
 	if s != nil && s.res != nil {
 		C.PQclear(s.res)
 		runtime.SetFinalizer(s, nil)
@@ -242,14 +565,131 @@ func (r *driverRows) Columns() []string {
 	return r.cols
 }
 
-func argErr(i int, argType string, err string) os.Error {
-	return os.NewError(fmt.Sprintf("arg %d as %s: %s", i, argType, err))
+// pgEpoch is the reference instant PostgreSQL's binary timestamp format
+// counts microseconds from (2000-01-01 00:00:00 UTC).
+var pgEpoch, _ = time.Parse(timeFormat, "2000-01-01 00:00:00.000000+00")
+
+func decodeNumeric(b []byte) string {
+	ndigits := int(binary.BigEndian.Uint16(b[0:2]))
+	weight := int(int16(binary.BigEndian.Uint16(b[2:4])))
+	sign := binary.BigEndian.Uint16(b[4:6])
+	dscale := int(binary.BigEndian.Uint16(b[6:8]))
+	if sign == 0xC000 {
+		return "NaN"
+	}
+	digits := make([]int, ndigits)
+	for i := 0; i < ndigits; i++ {
+		digits[i] = int(binary.BigEndian.Uint16(b[8+2*i : 10+2*i]))
+	}
+
+	digit := func(i int) int {
+		if i < 0 || i >= len(digits) {
+			return 0
+		}
+		return digits[i]
+	}
+
+	var buf bytes.Buffer
+	if sign == 0x4000 {
+		buf.WriteByte('-')
+	}
+	if weight < 0 {
+		buf.WriteByte('0')
+	} else {
+		for i := 0; i <= weight; i++ {
+			if i == 0 {
+				fmt.Fprintf(&buf, "%d", digit(i))
+			} else {
+				fmt.Fprintf(&buf, "%04d", digit(i))
+			}
+		}
+	}
+	if dscale > 0 {
+		buf.WriteByte('.')
+		for i := 0; i*4 < dscale; i++ {
+			fmt.Fprintf(&buf, "%04d", digit(weight+1+i))
+		}
+		// Truncate to the server-reported display scale.
+		s := buf.String()
+		dot := strings.IndexByte(s, '.')
+		if len(s)-dot-1 > dscale {
+			s = s[:dot+1+dscale]
+		}
+		return s
+	}
+	return buf.String()
 }
 
-func (r *driverRows) Next(dest []interface{}) os.Error {
+// decodeTimeOfDay formats a TIME value's microseconds-since-midnight using
+// PostgreSQL's default "HH:MM:SS[.ffffff]" text representation.
+func decodeTimeOfDay(micros int64) string {
+	sign := ""
+	if micros < 0 {
+		sign = "-"
+		micros = -micros
+	}
+	usec := micros % 1000000
+	secs := micros / 1000000
+	s := secs % 60
+	m := (secs / 60) % 60
+	h := secs / 3600
+	if usec == 0 {
+		return fmt.Sprintf("%s%02d:%02d:%02d", sign, h, m, s)
+	}
+	return fmt.Sprintf("%s%02d:%02d:%02d.%06d", sign, h, m, s, usec)
+}
+
+// formatUTCOffset renders a UTC offset in seconds as the "+HH" or "+HH:MM"
+// suffix PostgreSQL appends to timetz text output.
+func formatUTCOffset(secs int32) string {
+	sign := "+"
+	if secs < 0 {
+		sign = "-"
+		secs = -secs
+	}
+	h := secs / 3600
+	m := (secs % 3600) / 60
+	if m == 0 {
+		return fmt.Sprintf("%s%02d", sign, h)
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, h, m)
+}
+
+// formatInterval renders an INTERVAL's months/days/microseconds components
+// using PostgreSQL's default ("postgres") interval output style, e.g.
+// "1 year 2 mons 3 days 04:05:06".
+func formatInterval(months, days int32, micros int64) string {
+	var parts []string
+	years := months / 12
+	mons := months % 12
+	if years != 0 {
+		parts = append(parts, fmt.Sprintf("%d year%s", years, plural(years)))
+	}
+	if mons != 0 {
+		parts = append(parts, fmt.Sprintf("%d mon%s", mons, plural(mons)))
+	}
+	if days != 0 {
+		parts = append(parts, fmt.Sprintf("%d day%s", days, plural(days)))
+	}
+	if micros != 0 || len(parts) == 0 {
+		parts = append(parts, decodeTimeOfDay(micros))
+	}
+	return strings.Join(parts, " ")
+}
+
+// plural returns "s" unless n is exactly 1 or -1, matching the singular
+// units PostgreSQL's interval output uses for e.g. "1 year"/"-1 year".
+func plural(n int32) string {
+	if n == 1 || n == -1 {
+		return ""
+	}
+	return "s"
+}
+
+func (r *driverRows) Next(dest []driver.Value) error {
 	r.currRow++
 	if r.currRow >= r.nrows {
-		return os.EOF
+		return io.EOF
 	}
 
 	for i := 0; i < len(dest); i++ {
@@ -257,37 +697,54 @@ func (r *driverRows) Next(dest []interface{}) os.Error {
 			dest[i] = nil
 			continue
 		}
-		val := C.GoString(C.PQgetvalue(r.res, C.int(r.currRow), C.int(i)))
+		n := int(C.PQgetlength(r.res, C.int(r.currRow), C.int(i)))
+		raw := C.GoBytes(unsafe.Pointer(C.PQgetvalue(r.res, C.int(r.currRow), C.int(i))), C.int(n))
 		switch vtype := uint(C.PQftype(r.res, C.int(i))); vtype {
 		case BOOLOID:
-			if val == "t" {
-				dest[i] = "true"
-			} else {
-				dest[i] = "false"
-			}
+			dest[i] = raw[0] != 0
 		case BYTEAOID:
-			if !strings.HasPrefix(val, "\\x") {
-				return argErr(i, "[]byte", "invalid byte string format")
-			}
-			buf, err := hex.DecodeString(val[2:])
-			if err != nil {
-				return argErr(i, "[]byte", err.String())
-			}
-			dest[i] = buf
-		case CHAROID, BPCHAROID, VARCHAROID, TEXTOID,
-			INT2OID, INT4OID, INT8OID, OIDOID, XIDOID,
-			FLOAT8OID, FLOAT4OID,
-			DATEOID, TIMEOID, TIMESTAMPOID, TIMESTAMPTZOID, INTERVALOID, TIMETZOID,
-			NUMERICOID:
-			dest[i] = val
+			dest[i] = raw
+		case INT2OID:
+			dest[i] = int64(int16(binary.BigEndian.Uint16(raw)))
+		case INT4OID, OIDOID, XIDOID:
+			dest[i] = int64(int32(binary.BigEndian.Uint32(raw)))
+		case INT8OID:
+			dest[i] = int64(binary.BigEndian.Uint64(raw))
+		case FLOAT4OID:
+			dest[i] = float64(math.Float32frombits(binary.BigEndian.Uint32(raw)))
+		case FLOAT8OID:
+			dest[i] = math.Float64frombits(binary.BigEndian.Uint64(raw))
+		case NUMERICOID:
+			dest[i] = decodeNumeric(raw)
+		case TIMESTAMPOID, TIMESTAMPTZOID:
+			micros := int64(binary.BigEndian.Uint64(raw))
+			t := pgEpoch.Add(time.Duration(micros) * time.Microsecond)
+			dest[i] = t
+		case DATEOID:
+			days := int32(binary.BigEndian.Uint32(raw))
+			dest[i] = pgEpoch.AddDate(0, 0, int(days))
+		case TIMEOID:
+			micros := int64(binary.BigEndian.Uint64(raw))
+			dest[i] = decodeTimeOfDay(micros)
+		case TIMETZOID:
+			micros := int64(binary.BigEndian.Uint64(raw[0:8]))
+			zone := int32(binary.BigEndian.Uint32(raw[8:12]))
+			dest[i] = decodeTimeOfDay(micros) + formatUTCOffset(-zone)
+		case INTERVALOID:
+			micros := int64(binary.BigEndian.Uint64(raw[0:8]))
+			days := int32(binary.BigEndian.Uint32(raw[8:12]))
+			months := int32(binary.BigEndian.Uint32(raw[12:16]))
+			dest[i] = formatInterval(months, days, micros)
+		case CHAROID, BPCHAROID, VARCHAROID, TEXTOID, NAMEOID:
+			dest[i] = string(raw)
 		default:
-			return os.NewError(fmt.Sprintf("unsupported type oid: %d", vtype))
+			return fmt.Errorf("pgsqldriver: unsupported type oid for binary decode: %d", vtype)
 		}
 	}
 	return nil
 }
 
-func (r *driverRows) Close() os.Error {
+func (r *driverRows) Close() error {
 	if r.res != nil {
 		C.PQclear(r.res)
 		r.res = nil
@@ -296,7 +753,7 @@ func (r *driverRows) Close() os.Error {
 	return nil
 }
 
-func buildCArgs(params []interface{}) **C.char {
+func buildCArgs(params []driver.Value) **C.char {
 	sparams := make([]string, len(params))
 	for i, v := range params {
 		var str string
@@ -309,7 +766,7 @@ func buildCArgs(params []interface{}) **C.char {
 			} else {
 				str = "f"
 			}
-		case *time.Time:
+		case time.Time:
 			str = v.Format(timeFormat)
 		default:
 			str = fmt.Sprint(v)
@@ -326,4 +783,4 @@ func buildCArgs(params []interface{}) **C.char {
 
 func init() {
 	sql.Register("postgres", &postgresDriver{})
-}
\ No newline at end of file
+}
@@ -0,0 +1,750 @@
+// Package pgdriver is a pure-Go PostgreSQL driver for the database/sql
+// package. Unlike pgsqldriver, it speaks the PostgreSQL frontend/backend
+// protocol (version 3) directly over a net.Conn instead of binding to
+// libpq via cgo, so binaries that use it can be cross-compiled and
+// deployed without a system libpq.
+package pgdriver
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeFormat is the text representation sent to the server for time.Time
+// arguments; it matches pgsqldriver's cgo-based buildCArgs.
+const timeFormat = "2006-01-02 15:04:05.000000-07"
+
+func init() {
+	sql.Register("postgres-pure", &Driver{})
+}
+
+// Driver implements driver.Driver using a native implementation of the
+// PostgreSQL wire protocol. Register name: "postgres-pure".
+type Driver struct{}
+
+// Open creates a new database connection using the given connection string,
+// which uses the same 'keyword=value' syntax as libpq, e.g.
+// "host=localhost port=5432 user=foo dbname=bar password=secret".
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	params, err := parseDSN(name)
+	if err != nil {
+		return nil, err
+	}
+	host := params["host"]
+	if host == "" {
+		host = "localhost"
+	}
+	port := params["port"]
+	if port == "" {
+		port = "5432"
+	}
+	netConn, err := net.Dial("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	if upgraded, err := negotiateTLS(netConn, host, params["sslmode"]); err != nil {
+		netConn.Close()
+		return nil, err
+	} else {
+		netConn = upgraded
+	}
+	c := &conn{
+		c:  netConn,
+		br: bufio.NewReader(netConn),
+	}
+	if err := c.startup(params); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// sslRequestCode is the magic value libpq sends in place of a protocol
+// version to ask the server whether it will accept an SSL-wrapped
+// connection, per the SSLRequest preamble in the v3 protocol docs.
+const sslRequestCode = 80877103
+
+// negotiateTLS implements the SSLRequest preamble: before the startup
+// message, the client may ask the server whether it accepts TLS on this
+// connection. sslmode follows the subset of libpq's semantics this driver
+// supports: "" and "disable" never attempt TLS; "require", "verify-ca",
+// and "verify-full" attempt it and fail the connection outright if the
+// server declines or the handshake fails; any other value (e.g.
+// "prefer", "allow") attempts TLS but falls back to the plaintext
+// connection if the server declines.
+func negotiateTLS(nc net.Conn, host, sslmode string) (net.Conn, error) {
+	if sslmode == "" || sslmode == "disable" {
+		return nc, nil
+	}
+	required := sslmode == "require" || sslmode == "verify-ca" || sslmode == "verify-full"
+
+	var req [8]byte
+	binary.BigEndian.PutUint32(req[0:4], 8)
+	binary.BigEndian.PutUint32(req[4:8], sslRequestCode)
+	if _, err := nc.Write(req[:]); err != nil {
+		return nil, err
+	}
+	var resp [1]byte
+	if _, err := io.ReadFull(nc, resp[:]); err != nil {
+		return nil, err
+	}
+	if resp[0] != 'S' {
+		if required {
+			return nil, fmt.Errorf("pgdriver: server declined SSL requested by sslmode=%s", sslmode)
+		}
+		return nc, nil
+	}
+
+	conf := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	}
+	if sslmode == "verify-ca" || sslmode == "verify-full" {
+		conf.VerifyConnection = func(cs tls.ConnectionState) error {
+			opts := x509.VerifyOptions{Intermediates: x509.NewCertPool()}
+			if sslmode == "verify-full" {
+				opts.DNSName = host
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	tc := tls.Client(nc, conf)
+	if err := tc.Handshake(); err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
+
+// parseDSN parses a libpq-style 'keyword=value' connection string into a
+// map of parameter names to values.
+func parseDSN(name string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, kv := range strings.Fields(name) {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("pgdriver: invalid connection string segment %q", kv)
+		}
+		params[kv[:i]] = kv[i+1:]
+	}
+	return params, nil
+}
+
+// Backend message types we care about. See the PostgreSQL protocol docs:
+// http://www.postgresql.org/docs/current/static/protocol-message-formats.html
+const (
+	msgAuthentication  = 'R'
+	msgBackendKeyData  = 'K'
+	msgBindComplete    = '2'
+	msgCloseComplete   = '3'
+	msgCommandComplete = 'C'
+	msgDataRow         = 'D'
+	msgEmptyQueryResp  = 'I'
+	msgErrorResponse   = 'E'
+	msgNoData          = 'n'
+	msgNoticeResponse  = 'N'
+	msgParameterStatus = 'S'
+	msgParseComplete   = '1'
+	msgParamDescribe   = 't'
+	msgReadyForQuery   = 'Z'
+	msgRowDescription  = 'T'
+)
+
+const (
+	authOK                = 0
+	authCleartextPassword = 3
+	authMD5Password       = 5
+	authSASL              = 10
+	authSASLContinue      = 11
+	authSASLFinal         = 12
+)
+
+// conn is a single connection to a PostgreSQL backend.
+type conn struct {
+	c       net.Conn
+	br      *bufio.Reader
+	user    string
+	stmtNum int
+}
+
+var _ driver.Execer = (*conn)(nil)
+
+// send writes a message of the given type (or, for msgType == 0, an
+// untyped startup-style message) followed by its length and body.
+func (c *conn) send(msgType byte, body []byte) error {
+	buf := make([]byte, 0, 5+len(body))
+	if msgType != 0 {
+		buf = append(buf, msgType)
+	}
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(body)+4))
+	buf = append(buf, lenBuf[:]...)
+	buf = append(buf, body...)
+	_, err := c.c.Write(buf)
+	return err
+}
+
+// recv reads one backend message and returns its type and body (not
+// including the type byte or length word).
+func (c *conn) recv() (msgType byte, body []byte, err error) {
+	msgType, err = c.br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var lenBuf [4]byte
+	if _, err = io.ReadFull(c.br, lenBuf[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(lenBuf[:]) - 4
+	body = make([]byte, n)
+	if _, err = io.ReadFull(c.br, body); err != nil {
+		return 0, nil, err
+	}
+	return msgType, body, nil
+}
+
+func parseError(body []byte) error {
+	var msg string
+	for _, field := range bytes.Split(body, []byte{0}) {
+		if len(field) > 0 && field[0] == 'M' {
+			msg = string(field[1:])
+		}
+	}
+	if msg == "" {
+		msg = "unknown error"
+	}
+	return errors.New("pgdriver: " + msg)
+}
+
+// startup performs the protocol handshake: sending the StartupMessage,
+// answering any authentication challenge, and consuming backend parameter
+// status and key data messages until the server reports ReadyForQuery.
+func (c *conn) startup(params map[string]string) error {
+	user := params["user"]
+	if user == "" {
+		return errors.New("pgdriver: missing required parameter 'user'")
+	}
+	c.user = user
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, int32(196608)) // protocol version 3.0
+	writeParam := func(k, v string) {
+		buf.WriteString(k)
+		buf.WriteByte(0)
+		buf.WriteString(v)
+		buf.WriteByte(0)
+	}
+	writeParam("user", user)
+	if dbname := params["dbname"]; dbname != "" {
+		writeParam("database", dbname)
+	}
+	for k, v := range params {
+		switch k {
+		case "user", "dbname", "host", "port", "password", "sslmode":
+			continue
+		}
+		writeParam(k, v)
+	}
+	buf.WriteByte(0)
+	if err := c.send(0, buf.Bytes()); err != nil {
+		return err
+	}
+
+	for {
+		msgType, body, err := c.recv()
+		if err != nil {
+			return err
+		}
+		switch msgType {
+		case msgAuthentication:
+			if err := c.handleAuth(body, params["password"]); err != nil {
+				return err
+			}
+		case msgBackendKeyData, msgParameterStatus, msgNoticeResponse:
+			// Not currently surfaced to callers.
+		case msgErrorResponse:
+			return parseError(body)
+		case msgReadyForQuery:
+			return nil
+		default:
+			return fmt.Errorf("pgdriver: unexpected message %q during startup", msgType)
+		}
+	}
+}
+
+func (c *conn) handleAuth(body []byte, password string) error {
+	authType := binary.BigEndian.Uint32(body[0:4])
+	switch authType {
+	case authOK:
+		return nil
+	case authCleartextPassword:
+		return c.sendPasswordMessage(password)
+	case authMD5Password:
+		salt := body[4:8]
+		return c.sendPasswordMessage(md5Password(c.user, password, salt))
+	case authSASL:
+		return c.doSCRAMSHA256(password, body[4:])
+	default:
+		return fmt.Errorf("pgdriver: unsupported authentication type %d", authType)
+	}
+}
+
+func (c *conn) sendPasswordMessage(s string) error {
+	return c.send('p', append([]byte(s), 0))
+}
+
+// md5Password computes the 'md5'-prefixed password hash PostgreSQL expects
+// in response to an AuthenticationMD5Password request.
+func md5Password(user, password string, salt []byte) string {
+	inner := md5.Sum([]byte(password + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// doSCRAMSHA256 implements the client side of SCRAM-SHA-256 (RFC 5802) as
+// used by PostgreSQL's AuthenticationSASL/SASLContinue/SASLFinal exchange.
+func (c *conn) doSCRAMSHA256(password string, mechanisms []byte) error {
+	supported := false
+	for _, m := range strings.Split(strings.TrimRight(string(mechanisms), "\x00"), "\x00") {
+		if m == "SCRAM-SHA-256" {
+			supported = true
+		}
+	}
+	if !supported {
+		return errors.New("pgdriver: server does not offer SCRAM-SHA-256")
+	}
+
+	nonceBytes := make([]byte, 18)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return err
+	}
+	clientNonce := base64.StdEncoding.EncodeToString(nonceBytes)
+	clientFirstBare := "n=,r=" + clientNonce
+	clientFirst := "n,," + clientFirstBare
+
+	var initial bytes.Buffer
+	initial.WriteString("SCRAM-SHA-256")
+	initial.WriteByte(0)
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(clientFirst)))
+	initial.Write(lenBuf[:])
+	initial.WriteString(clientFirst)
+	if err := c.send('p', initial.Bytes()); err != nil {
+		return err
+	}
+
+	msgType, resp, err := c.recv()
+	if err != nil {
+		return err
+	}
+	if msgType != msgAuthentication || binary.BigEndian.Uint32(resp[0:4]) != authSASLContinue {
+		return fmt.Errorf("pgdriver: expected AuthenticationSASLContinue, got %q", msgType)
+	}
+	serverFirst := string(resp[4:])
+
+	var serverNonce, saltB64 string
+	var iterCount int
+	for _, field := range strings.Split(serverFirst, ",") {
+		switch {
+		case strings.HasPrefix(field, "r="):
+			serverNonce = field[2:]
+		case strings.HasPrefix(field, "s="):
+			saltB64 = field[2:]
+		case strings.HasPrefix(field, "i="):
+			iterCount, _ = strconv.Atoi(field[2:])
+		}
+	}
+	if !strings.HasPrefix(serverNonce, clientNonce) {
+		return errors.New("pgdriver: server SCRAM nonce does not extend client nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(saltB64)
+	if err != nil {
+		return err
+	}
+
+	clientFinalWithoutProof := "c=biws,r=" + serverNonce
+	authMessage := clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof
+
+	saltedPassword := pbkdf2HMACSHA256([]byte(password), salt, iterCount, sha256.Size)
+	clientKey := hmacSHA256(saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	clientSignature := hmacSHA256(storedKey[:], []byte(authMessage))
+	clientProof := make([]byte, len(clientKey))
+	for i := range clientProof {
+		clientProof[i] = clientKey[i] ^ clientSignature[i]
+	}
+	clientFinal := clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(clientProof)
+	if err := c.send('p', []byte(clientFinal)); err != nil {
+		return err
+	}
+
+	msgType, resp, err = c.recv()
+	if err != nil {
+		return err
+	}
+	if msgType != msgAuthentication || binary.BigEndian.Uint32(resp[0:4]) != authSASLFinal {
+		return fmt.Errorf("pgdriver: expected AuthenticationSASLFinal, got %q", msgType)
+	}
+	// resp[4:] carries "v=<base64 server signature>"; verifying it protects
+	// against a spoofed server but is not required to authenticate.
+	return nil
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// pbkdf2HMACSHA256 implements RFC 2898 PBKDF2 with HMAC-SHA256, which is
+// all SCRAM-SHA-256 needs. It's small enough to hand-roll here rather than
+// pull in a third-party dependency for a single call site.
+func pbkdf2HMACSHA256(password, salt []byte, iter, keyLen int) []byte {
+	mac := hmac.New(sha256.New, password)
+	var block []byte
+	for i := 1; len(block) < keyLen; i++ {
+		mac.Reset()
+		mac.Write(salt)
+		var ibuf [4]byte
+		binary.BigEndian.PutUint32(ibuf[:], uint32(i))
+		mac.Write(ibuf[:])
+		u := mac.Sum(nil)
+		t := append([]byte(nil), u...)
+		for j := 1; j < iter; j++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(nil)
+			for k := range t {
+				t[k] ^= u[k]
+			}
+		}
+		block = append(block, t...)
+	}
+	return block[:keyLen]
+}
+
+// Exec implements driver.Execer for statements with no arguments via the
+// simple query protocol. Statements with arguments fall back to the
+// Prepare/stmt.Exec path, which uses the extended query protocol.
+func (c *conn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	if len(args) > 0 {
+		return nil, driver.ErrSkip
+	}
+	if err := c.send('Q', append([]byte(query), 0)); err != nil {
+		return nil, err
+	}
+	return c.readSimpleQueryResult()
+}
+
+func (c *conn) readSimpleQueryResult() (driver.Result, error) {
+	var result driver.Result
+	for {
+		msgType, body, err := c.recv()
+		if err != nil {
+			return nil, err
+		}
+		switch msgType {
+		case msgRowDescription, msgDataRow, msgNoData:
+			// Discarded: Exec doesn't return rows.
+		case msgCommandComplete:
+			result = parseCommandTag(body)
+		case msgEmptyQueryResp:
+			result = driver.RowsAffected(0)
+		case msgErrorResponse:
+			return nil, parseError(body)
+		case msgReadyForQuery:
+			return result, nil
+		}
+	}
+}
+
+func parseCommandTag(body []byte) driver.Result {
+	tag := strings.TrimRight(string(body), "\x00")
+	fields := strings.Fields(tag)
+	if len(fields) == 0 {
+		return driver.RowsAffected(0)
+	}
+	n, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return driver.RowsAffected(0)
+	}
+	return driver.RowsAffected(n)
+}
+
+// Prepare parses and describes a statement using the extended query
+// protocol and returns a reusable *stmt.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	name := "pgdriver_" + strconv.Itoa(c.stmtNum)
+	c.stmtNum++
+
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	buf.WriteByte(0)
+	buf.WriteString(query)
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.BigEndian, int16(0))
+	if err := c.send('P', buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var describe bytes.Buffer
+	describe.WriteByte('S')
+	describe.WriteString(name)
+	describe.WriteByte(0)
+	if err := c.send('D', describe.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := c.send('S', nil); err != nil {
+		return nil, err
+	}
+
+	var nparams int
+	var fields []string
+	for {
+		msgType, body, err := c.recv()
+		if err != nil {
+			return nil, err
+		}
+		switch msgType {
+		case msgParseComplete:
+		case msgParamDescribe:
+			nparams = int(binary.BigEndian.Uint16(body[0:2]))
+		case msgRowDescription:
+			fields = parseFieldNames(body)
+		case msgNoData:
+		case msgErrorResponse:
+			return nil, parseError(body)
+		case msgReadyForQuery:
+			return &stmt{c: c, name: name, nparams: nparams, fields: fields}, nil
+		}
+	}
+}
+
+func parseFieldNames(body []byte) []string {
+	n := int(binary.BigEndian.Uint16(body[0:2]))
+	fields := make([]string, n)
+	pos := 2
+	for i := 0; i < n; i++ {
+		end := pos
+		for body[end] != 0 {
+			end++
+		}
+		fields[i] = string(body[pos:end])
+		pos = end + 1 + 18 // skip the fixed-size portion of each field descriptor
+	}
+	return fields
+}
+
+func (c *conn) Close() error {
+	c.send('X', nil)
+	return c.c.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	if _, err := c.Exec("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *conn) Commit() error {
+	_, err := c.Exec("COMMIT", nil)
+	return err
+}
+
+func (c *conn) Rollback() error {
+	_, err := c.Exec("ROLLBACK", nil)
+	return err
+}
+
+// stmt is a statement prepared on the backend via the extended query
+// protocol (Parse/Bind/Describe/Execute/Sync).
+type stmt struct {
+	c       *conn
+	name    string
+	nparams int
+	fields  []string
+}
+
+func (s *stmt) NumInput() int { return s.nparams }
+
+func (s *stmt) Close() error {
+	var buf bytes.Buffer
+	buf.WriteByte('S')
+	buf.WriteString(s.name)
+	buf.WriteByte(0)
+	if err := s.c.send('C', buf.Bytes()); err != nil {
+		return err
+	}
+	if err := s.c.send('S', nil); err != nil {
+		return err
+	}
+	for {
+		msgType, body, err := s.c.recv()
+		if err != nil {
+			return err
+		}
+		switch msgType {
+		case msgErrorResponse:
+			return parseError(body)
+		case msgReadyForQuery:
+			return nil
+		}
+	}
+}
+
+func (s *stmt) bind(args []driver.Value) error {
+	var buf bytes.Buffer
+	buf.WriteByte(0) // unnamed portal
+	buf.WriteString(s.name)
+	buf.WriteByte(0)
+	binary.Write(&buf, binary.BigEndian, int16(0)) // all params sent as text
+	binary.Write(&buf, binary.BigEndian, int16(len(args)))
+	for _, v := range args {
+		text := formatValue(v)
+		if text == nil {
+			binary.Write(&buf, binary.BigEndian, int32(-1))
+			continue
+		}
+		binary.Write(&buf, binary.BigEndian, int32(len(text)))
+		buf.Write(text)
+	}
+	binary.Write(&buf, binary.BigEndian, int16(0)) // all results as text
+	if err := s.c.send('B', buf.Bytes()); err != nil {
+		return err
+	}
+	if err := s.c.send('E', append([]byte{0}, 0, 0, 0, 0)); err != nil {
+		return err
+	}
+	return s.c.send('S', nil)
+}
+
+func formatValue(v driver.Value) []byte {
+	if v == nil {
+		return nil
+	}
+	switch v := v.(type) {
+	case []byte:
+		return []byte("\\x" + hex.EncodeToString(v))
+	case bool:
+		if v {
+			return []byte("t")
+		}
+		return []byte("f")
+	case time.Time:
+		return []byte(v.Format(timeFormat))
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.bind(args); err != nil {
+		return nil, err
+	}
+	var result driver.Result
+	for {
+		msgType, body, err := s.c.recv()
+		if err != nil {
+			return nil, err
+		}
+		switch msgType {
+		case msgBindComplete, msgDataRow:
+		case msgCommandComplete:
+			result = parseCommandTag(body)
+		case msgErrorResponse:
+			return nil, parseError(body)
+		case msgReadyForQuery:
+			return result, nil
+		}
+	}
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.bind(args); err != nil {
+		return nil, err
+	}
+	r := &rows{fields: s.fields}
+	for {
+		msgType, body, err := s.c.recv()
+		if err != nil {
+			return nil, err
+		}
+		switch msgType {
+		case msgBindComplete:
+		case msgDataRow:
+			r.data = append(r.data, parseDataRow(body))
+		case msgCommandComplete:
+		case msgErrorResponse:
+			return nil, parseError(body)
+		case msgReadyForQuery:
+			return r, nil
+		}
+	}
+}
+
+func parseDataRow(body []byte) [][]byte {
+	n := int(binary.BigEndian.Uint16(body[0:2]))
+	row := make([][]byte, n)
+	pos := 2
+	for i := 0; i < n; i++ {
+		l := int32(binary.BigEndian.Uint32(body[pos : pos+4]))
+		pos += 4
+		if l < 0 {
+			row[i] = nil
+			continue
+		}
+		row[i] = body[pos : pos+int(l)]
+		pos += int(l)
+	}
+	return row
+}
+
+// rows holds the fully-buffered result of an extended-query Execute; the
+// backend has already sent every DataRow by the time Query returns.
+type rows struct {
+	fields []string
+	data   [][][]byte
+	cur    int
+}
+
+func (r *rows) Columns() []string { return r.fields }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.cur >= len(r.data) {
+		return io.EOF
+	}
+	row := r.data[r.cur]
+	r.cur++
+	for i, col := range row {
+		if col == nil {
+			dest[i] = nil
+		} else {
+			dest[i] = string(col)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// +build postgres
+
+package sqltest
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"testing"
+
+	_ "github.com/jbarham/gopgsqldriver"
+)
+
+// copier is the interface pgsqldriver's *driverConn exposes for bulk
+// loading via COPY FROM STDIN/TO STDOUT; see gopgsqldriver's CopyIn/CopyOut.
+type copier interface {
+	CopyIn(table string, columns []string) (io.WriteCloser, error)
+	CopyOut(query string) (io.ReadCloser, error)
+}
+
+func openCopyConn(t *testing.T) (*sql.DB, copier, bool) {
+	dsn, ok := getenvOk("GOSQLTEST_PGURL")
+	if !ok {
+		t.Logf("GOSQLTEST_PGURL not set, skipping")
+		return nil, nil, false
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	conn, err := db.Driver().Open(dsn)
+	if err != nil {
+		t.Fatalf("driver open: %v", err)
+	}
+	c, ok := conn.(copier)
+	if !ok {
+		t.Fatal("driverConn does not implement CopyIn")
+	}
+	return db, c, true
+}
+
+func TestCopyIn_Postgres(t *testing.T) {
+	db, c, ok := openCopyConn(t)
+	if !ok {
+		return
+	}
+	defer db.Close()
+
+	db.Exec("DROP TABLE IF EXISTS " + TablePrefix + "copytest")
+	if _, err := db.Exec("CREATE TABLE " + TablePrefix + "copytest (id integer, name text)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	w, err := c.CopyIn(TablePrefix+"copytest", []string{"id", "name"})
+	if err != nil {
+		t.Fatalf("CopyIn: %v", err)
+	}
+	bw := bufio.NewWriter(w)
+	const nRows = 100000
+	for i := 0; i < nRows; i++ {
+		fmt.Fprintf(bw, "%d\trow%d\n", i, i)
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("CopyIn Close: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM " + TablePrefix + "copytest").Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != nRows {
+		t.Errorf("got %d rows, want %d", count, nRows)
+	}
+}
+
+func TestCopyInCancel_Postgres(t *testing.T) {
+	db, c, ok := openCopyConn(t)
+	if !ok {
+		return
+	}
+	defer db.Close()
+
+	db.Exec("DROP TABLE IF EXISTS " + TablePrefix + "copycancel")
+	if _, err := db.Exec("CREATE TABLE " + TablePrefix + "copycancel (id integer)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	w, err := c.CopyIn(TablePrefix+"copycancel", []string{"id"})
+	if err != nil {
+		t.Fatalf("CopyIn: %v", err)
+	}
+	io.WriteString(w, "1\n")
+	// A genuinely malformed row (two tab-separated fields for a
+	// one-column table) must fail the COPY rather than commit silently.
+	io.WriteString(w, "2\textra\n")
+	if err := w.Close(); err == nil {
+		t.Errorf("expected Close to report the malformed copy data, got nil")
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM " + TablePrefix + "copycancel").Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("got %d rows after aborted copy, want 0", count)
+	}
+}
+
+func TestCopyOut_Postgres(t *testing.T) {
+	db, c, ok := openCopyConn(t)
+	if !ok {
+		return
+	}
+	defer db.Close()
+
+	db.Exec("DROP TABLE IF EXISTS " + TablePrefix + "copyouttest")
+	if _, err := db.Exec("CREATE TABLE " + TablePrefix + "copyouttest (id integer, name text)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	const nRows = 1000
+	w, err := c.CopyIn(TablePrefix+"copyouttest", []string{"id", "name"})
+	if err != nil {
+		t.Fatalf("CopyIn: %v", err)
+	}
+	bw := bufio.NewWriter(w)
+	for i := 0; i < nRows; i++ {
+		fmt.Fprintf(bw, "%d\trow%d\n", i, i)
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("CopyIn Close: %v", err)
+	}
+
+	r, err := c.CopyOut("COPY " + TablePrefix + "copyouttest (id, name) TO STDOUT")
+	if err != nil {
+		t.Fatalf("CopyOut: %v", err)
+	}
+	var lines int
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines++
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("CopyOut Close: %v", err)
+	}
+	if lines != nRows {
+		t.Errorf("got %d rows, want %d", lines, nRows)
+	}
+}
@@ -0,0 +1,7 @@
+// +build mysql
+
+package sqltest
+
+import (
+	_ "github.com/go-sql-driver/mysql"
+)
@@ -0,0 +1,73 @@
+// +build postgres
+
+package sqltest
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	_ "github.com/jbarham/gopgsqldriver/pgdriver"
+)
+
+// TestPgDriverSmoke exercises the pure-Go "postgres-pure" driver's basic
+// Open/Exec/Prepare/Query path (and the auth handshake that gets it there)
+// against a live server, since pgdriver speaks the wire protocol directly
+// instead of delegating to libpq like pgsqldriver does.
+func TestPgDriverSmoke(t *testing.T) {
+	dsn, ok := getenvOk("GOSQLTEST_PGURL")
+	if !ok {
+		t.Logf("GOSQLTEST_PGURL not set, skipping")
+		return
+	}
+	db, err := sql.Open("postgres-pure", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	prefix := newTestPrefix()
+	defer dropTestTables(db, prefix)
+
+	if _, err := db.Exec("CREATE TABLE " + prefix + "foo (id integer, name text)"); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	stmt, err := db.Prepare("INSERT INTO " + prefix + "foo (id, name) VALUES ($1, $2)")
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	defer stmt.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := stmt.Exec(i, fmt.Sprintf("row%d", i)); err != nil {
+			t.Fatalf("exec %d: %v", i, err)
+		}
+	}
+
+	rows, err := db.Query("SELECT id, name FROM " + prefix + "foo ORDER BY id")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", id, name))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	want := []string{"0:row0", "1:row1", "2:row2"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows %v, want %d rows %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
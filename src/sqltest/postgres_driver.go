@@ -0,0 +1,7 @@
+// +build postgres
+
+package sqltest
+
+import (
+	_ "github.com/jbarham/gopgsqldriver"
+)
@@ -1,7 +1,9 @@
 package sqltest
 
 import (
+	cryptorand "crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -17,19 +19,24 @@ type Tester interface {
 }
 
 var (
-	sqliteCgo   Tester = sqliteDBCgo{}
-	sqliteNoCgo Tester = sqliteDBNoCgo{}
+	sqliteCgo      Tester = sqliteDBCgo{}
+	sqliteNoCgo    Tester = sqliteDBNoCgo{}
+	postgresTester Tester = postgresDB{}
+	mysqlTester    Tester = mysqlDB{}
 )
 
 const TablePrefix = "gosqltest_"
 
 type sqliteDBCgo struct{}
 type sqliteDBNoCgo struct{}
+type postgresDB struct{}
+type mysqlDB struct{}
 
 type params struct {
 	dbType Tester
 	*testing.T
 	*sql.DB
+	prefix string
 }
 
 func (t params) mustExec(sql string, args ...interface{}) sql.Result {
@@ -42,17 +49,16 @@ func (t params) mustExec(sql string, args ...interface{}) sql.Result {
 
 var qrx = regexp.MustCompile(`\?`)
 
-// q converts "?" characters to $1, $2, $n on postgres, :1, :2, :n on Oracle
+// q converts "?" characters to $1, $2, $n on postgres; MySQL and SQLite
+// both accept "?" natively, so it's a no-op for them.
 func (t params) q(sql string) string {
-	var pref string
-	switch t.dbType {
-	default:
+	if t.dbType != postgresTester {
 		return sql
 	}
 	n := 0
 	return qrx.ReplaceAllStringFunc(sql, func(string) string {
 		n++
-		return pref + strconv.Itoa(n)
+		return "$" + strconv.Itoa(n)
 	})
 }
 
@@ -66,7 +72,7 @@ func (sqliteDBCgo) RunTest(t *testing.T, fn func(params)) {
 	if err != nil {
 		t.Fatalf("foo.db open fail: %v", err)
 	}
-	fn(params{sqliteCgo, t, db})
+	fn(params{sqliteCgo, t, db, TablePrefix})
 }
 
 func (sqliteDBNoCgo) RunTest(t *testing.T, fn func(params)) {
@@ -79,29 +85,83 @@ func (sqliteDBNoCgo) RunTest(t *testing.T, fn func(params)) {
 	if err != nil {
 		t.Fatalf("foo.db open fail: %v", err)
 	}
-	fn(params{sqliteNoCgo, t, db})
+	fn(params{sqliteNoCgo, t, db, TablePrefix})
+}
+
+// newTestPrefix returns a table-name prefix unique to this test run, so
+// concurrent or leftover runs against a shared Postgres/MySQL server can't
+// collide.
+func newTestPrefix() string {
+	b := make([]byte, 8)
+	cryptorand.Read(b)
+	return TablePrefix + hex.EncodeToString(b) + "_"
+}
+
+// dropTestTables drops every table the conformance suite is known to
+// create under prefix, ignoring errors for tables that were never
+// created by the test that ran.
+func dropTestTables(db *sql.DB, prefix string) {
+	for _, name := range []string{"foo", "t"} {
+		db.Exec("DROP TABLE IF EXISTS " + prefix + name)
+	}
+}
+
+func (postgresDB) RunTest(t *testing.T, fn func(params)) {
+	dsn, ok := getenvOk("GOSQLTEST_PGURL")
+	if !ok {
+		t.Logf("GOSQLTEST_PGURL not set, skipping")
+		return
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("postgres open fail: %v", err)
+	}
+	defer db.Close()
+	prefix := newTestPrefix()
+	defer dropTestTables(db, prefix)
+	fn(params{postgresTester, t, db, prefix})
+}
+
+func (mysqlDB) RunTest(t *testing.T, fn func(params)) {
+	dsn, ok := getenvOk("GOSQLTEST_MYSQLURL")
+	if !ok {
+		t.Logf("GOSQLTEST_MYSQLURL not set, skipping")
+		return
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("mysql open fail: %v", err)
+	}
+	defer db.Close()
+	prefix := newTestPrefix()
+	defer dropTestTables(db, prefix)
+	fn(params{mysqlTester, t, db, prefix})
 }
 
 func sqlBlobParam(t params, size int) string {
 	switch t.dbType {
 	case sqliteCgo, sqliteNoCgo:
 		return fmt.Sprintf("blob[%d]", size)
+	case postgresTester:
+		return "bytea"
 	}
 	return fmt.Sprintf("VARBINARY(%d)", size)
 }
 
 func TestBlobs_SQLite_CGO(t *testing.T)   { sqliteCgo.RunTest(t, testBlobs) }
 func TestBlobs_SQLite_NOCGO(t *testing.T) { sqliteNoCgo.RunTest(t, testBlobs) }
+func TestBlobs_Postgres(t *testing.T)     { postgresTester.RunTest(t, testBlobs) }
+func TestBlobs_MySQL(t *testing.T)        { mysqlTester.RunTest(t, testBlobs) }
 
 func testBlobs(t params) {
 	var blob = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
-	t.mustExec("create table " + TablePrefix + "foo (id integer primary key, bar " + sqlBlobParam(t, 16) + ")")
-	t.mustExec(t.q("insert into "+TablePrefix+"foo (id, bar) values(?,?)"), 0, blob)
+	t.mustExec("create table " + t.prefix + "foo (id integer primary key, bar " + sqlBlobParam(t, 16) + ")")
+	t.mustExec(t.q("insert into "+t.prefix+"foo (id, bar) values(?,?)"), 0, blob)
 
 	want := fmt.Sprintf("%x", blob)
 
 	b := make([]byte, 16)
-	err := t.QueryRow(t.q("select bar from "+TablePrefix+"foo where id = ?"), 0).Scan(&b)
+	err := t.QueryRow(t.q("select bar from "+t.prefix+"foo where id = ?"), 0).Scan(&b)
 	got := fmt.Sprintf("%x", b)
 	if err != nil {
 		t.Errorf("[]byte scan: %v", err)
@@ -109,7 +169,7 @@ func testBlobs(t params) {
 		t.Errorf("for []byte, got %q; want %q", got, want)
 	}
 
-	err = t.QueryRow(t.q("select bar from "+TablePrefix+"foo where id = ?"), 0).Scan(&got)
+	err = t.QueryRow(t.q("select bar from "+t.prefix+"foo where id = ?"), 0).Scan(&got)
 	want = string(blob)
 	if err != nil {
 		t.Errorf("string scan: %v", err)
@@ -120,17 +180,19 @@ func testBlobs(t params) {
 
 func TestManyQueryRow_SQLite_CGO(t *testing.T)   { sqliteCgo.RunTest(t, testManyQueryRow) }
 func TestManyQueryRow_SQLite_NOCGO(t *testing.T) { sqliteNoCgo.RunTest(t, testManyQueryRow) }
+func TestManyQueryRow_Postgres(t *testing.T)     { postgresTester.RunTest(t, testManyQueryRow) }
+func TestManyQueryRow_MySQL(t *testing.T)        { mysqlTester.RunTest(t, testManyQueryRow) }
 
 func testManyQueryRow(t params) {
 	if testing.Short() {
 		t.Logf("skipping in short mode")
 		return
 	}
-	t.mustExec("create table " + TablePrefix + "foo (id integer primary key, name varchar(50))")
-	t.mustExec(t.q("insert into "+TablePrefix+"foo (id, name) values(?,?)"), 1, "bob")
+	t.mustExec("create table " + t.prefix + "foo (id integer primary key, name varchar(50))")
+	t.mustExec(t.q("insert into "+t.prefix+"foo (id, name) values(?,?)"), 1, "bob")
 	var name string
 	for i := 0; i < 10000; i++ {
-		err := t.QueryRow(t.q("select name from "+TablePrefix+"foo where id = ?"), 1).Scan(&name)
+		err := t.QueryRow(t.q("select name from "+t.prefix+"foo where id = ?"), 1).Scan(&name)
 		if err != nil || name != "bob" {
 			t.Fatalf("on query %d: err=%v, name=%q", i, err, name)
 		}
@@ -139,6 +201,8 @@ func testManyQueryRow(t params) {
 
 func TestTxQuery_SQLite_CGO(t *testing.T)   { sqliteCgo.RunTest(t, testTxQuery) }
 func TestTxQuery_SQLite_NOCGO(t *testing.T) { sqliteNoCgo.RunTest(t, testTxQuery) }
+func TestTxQuery_Postgres(t *testing.T)     { postgresTester.RunTest(t, testTxQuery) }
+func TestTxQuery_MySQL(t *testing.T)        { mysqlTester.RunTest(t, testTxQuery) }
 
 func testTxQuery(t params) {
 	tx, err := t.Begin()
@@ -147,17 +211,17 @@ func testTxQuery(t params) {
 	}
 	defer tx.Rollback()
 
-	_, err = t.DB.Exec("create table " + TablePrefix + "foo (id integer primary key, name varchar(50))")
+	_, err = t.DB.Exec("create table " + t.prefix + "foo (id integer primary key, name varchar(50))")
 	if err != nil {
-		t.Logf("cannot drop table "+TablePrefix+"foo: %s", err)
+		t.Logf("cannot drop table "+t.prefix+"foo: %s", err)
 	}
 
-	_, err = tx.Exec(t.q("insert into "+TablePrefix+"foo (id, name) values(?,?)"), 1, "bob")
+	_, err = tx.Exec(t.q("insert into "+t.prefix+"foo (id, name) values(?,?)"), 1, "bob")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	r, err := tx.Query(t.q("select name from "+TablePrefix+"foo where id = ?"), 1)
+	r, err := tx.Query(t.q("select name from "+t.prefix+"foo where id = ?"), 1)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -179,14 +243,16 @@ func testTxQuery(t params) {
 
 func TestPreparedStmt_SQLite_CGO(t *testing.T)   { sqliteCgo.RunTest(t, testPreparedStmt) }
 func TestPreparedStmt_SQLite_NOCGO(t *testing.T) { sqliteNoCgo.RunTest(t, testPreparedStmt) }
+func TestPreparedStmt_Postgres(t *testing.T)     { postgresTester.RunTest(t, testPreparedStmt) }
+func TestPreparedStmt_MySQL(t *testing.T)        { mysqlTester.RunTest(t, testPreparedStmt) }
 
 func testPreparedStmt(t params) {
-	t.mustExec("CREATE TABLE " + TablePrefix + "t (count INT)")
-	sel, err := t.Prepare("SELECT count FROM " + TablePrefix + "t ORDER BY count DESC")
+	t.mustExec("CREATE TABLE " + t.prefix + "t (count INT)")
+	sel, err := t.Prepare("SELECT count FROM " + t.prefix + "t ORDER BY count DESC")
 	if err != nil {
 		t.Fatalf("prepare 1: %v", err)
 	}
-	ins, err := t.Prepare(t.q("INSERT INTO " + TablePrefix + "t (count) VALUES (?)"))
+	ins, err := t.Prepare(t.q("INSERT INTO " + t.prefix + "t (count) VALUES (?)"))
 	if err != nil {
 		t.Fatalf("prepare 2: %v", err)
 	}